@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+
+	"github.com/ghodss/yaml"
+)
+
+// ScoringNode is one node of the scoring tree as expressed in
+// scoring.yaml. Leaf nodes set Expr and have no Children; interior nodes
+// set Children and are merged together, weighted by each child's Weight.
+// If none of a node's children set a Weight, they're merged with equal
+// weight (the same as passing nil to Merge).
+type ScoringNode struct {
+	Path     string        `json:"path"`
+	Goal     string        `json:"goal,omitempty"` // "bigger" or "smaller"
+	Expr     string        `json:"expr,omitempty"`
+	Norm     string        `json:"norm,omitempty"` // zscore (default), robustz, minmax, ranknormal
+	Weight   float64       `json:"weight,omitempty"`
+	Children []ScoringNode `json:"children,omitempty"`
+}
+
+// ScoringConfig is the top-level document in scoring.yaml. Financial is
+// scored separately in Go (see ScoreFinancial) since it depends on
+// Input-level fields like income and home equity, not just a City.
+type ScoringConfig struct {
+	Dimensions      []ScoringNode `json:"dimensions"`
+	FinancialWeight float64       `json:"financial_weight"`
+}
+
+func LoadScoringConfig(path string) (*ScoringConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg ScoringConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// weightSumTolerance accounts for the usual float64 slop in hand-picked
+// weights (e.g. 0.35+0.35+0.3 doesn't land on exactly 1.0).
+const weightSumTolerance = 1e-9
+
+// BuildScoreSet turns a ScoringNode into a ScoreSet, recursing into
+// Children and merging them by Weight. Returns an error, naming the
+// offending node's Path, if a leaf's expr is malformed or a node's
+// children weights are missing from some (but not all) children or don't
+// sum to 1.0 — a mis-written scoring.yaml shouldn't take the program down
+// with a bare panic.
+func BuildScoreSet(node ScoringNode, cities []City) (*ScoreSet, error) {
+	if len(node.Children) == 0 {
+		goal := BIGGER
+		if node.Goal == "smaller" {
+			goal = SMALLER
+		}
+		expr, err := CompileExpr(node.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("scoring.yaml: %s: %v", node.Path, err)
+		}
+		// Field resolution only depends on City's static shape, not on any
+		// particular city's data, so a non-ErrFieldMissing failure (a bad
+		// function name, a non-numeric field) is the same for every city.
+		// Catch it here against the zero City rather than per row.
+		if _, err := expr.Eval(City{}); err != nil {
+			var missing ErrFieldMissing
+			if !errors.As(err, &missing) {
+				return nil, fmt.Errorf("scoring.yaml: %s: %v", node.Path, err)
+			}
+		}
+		return NewScoreSetWith(node.Path, cities, goal, normalizerFor(node.Norm), func(city City) (float64, bool) {
+			val, err := expr.Eval(city)
+			if err != nil {
+				var missing ErrFieldMissing
+				if errors.As(err, &missing) {
+					return 0, false
+				}
+				log.Fatalf("scoring.yaml: %s: %v (should have been caught validating the expr)", node.Path, err)
+			}
+			return val, true
+		}), nil
+	}
+
+	sets := make([]*ScoreSet, len(node.Children))
+	weighted := 0
+	for i, child := range node.Children {
+		set, err := BuildScoreSet(child, cities)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+		if child.Weight != 0 {
+			weighted++
+		}
+	}
+
+	var weights []float64
+	switch weighted {
+	case 0:
+		weights = nil // no child set a weight: merge with equal weight
+	case len(node.Children):
+		weights = make([]float64, len(node.Children))
+		var total float64
+		for i, child := range node.Children {
+			weights[i] = child.Weight
+			total += child.Weight
+		}
+		if math.Abs(total-1.0) > weightSumTolerance {
+			return nil, fmt.Errorf("scoring.yaml: %s: children weights sum to %v, want 1.0", node.Path, total)
+		}
+	default:
+		return nil, fmt.Errorf("scoring.yaml: %s: %d of %d children set a weight; set all of them or none", node.Path, weighted, len(node.Children))
+	}
+
+	return Merge(sets, weights), nil
+}
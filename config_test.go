@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestBuildScoreSetBadExprReturnsError(t *testing.T) {
+	node := ScoringNode{
+		Path: "/Test/BadFunc",
+		Expr: "nosuchfunc(1)",
+	}
+	if _, err := BuildScoreSet(node, []City{{Name: "Austin"}}); err == nil {
+		t.Fatal("BuildScoreSet with an unknown function in expr returned no error")
+	}
+}
+
+func TestBuildScoreSetMissingFieldIsNotAnError(t *testing.T) {
+	node := ScoringNode{
+		Path: "/Test/Missing",
+		Expr: "nosuchfield.value",
+	}
+	if _, err := BuildScoreSet(node, []City{{Name: "Austin"}}); err != nil {
+		t.Fatalf("BuildScoreSet with an unresolved field path returned an error: %v", err)
+	}
+}
+
+func TestBuildScoreSetPartialChildWeightsError(t *testing.T) {
+	node := ScoringNode{
+		Path: "/Test/Partial",
+		Children: []ScoringNode{
+			{Path: "/Test/Partial/A", Expr: "1", Weight: 0.5},
+			{Path: "/Test/Partial/B", Expr: "1"},
+		},
+	}
+	if _, err := BuildScoreSet(node, []City{{Name: "Austin"}}); err == nil {
+		t.Fatal("BuildScoreSet with partially-weighted children returned no error")
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expr is a compiled scoring.yaml expression, ready to be evaluated
+// against any number of cities without re-lexing or re-parsing. Build one
+// with CompileExpr.
+type Expr struct {
+	src  string
+	node exprNode
+}
+
+// CompileExpr parses a small arithmetic expression over a City's fields,
+// as used by scoring.yaml leaf nodes, into a reusable Expr. Supported
+// syntax: + - * / with usual precedence, unary minus, parentheses,
+// numeric literals, the builtin abs(), the constant usaverage, and dotted
+// field paths that match the City struct's json tags (e.g.
+// "crime.violent", "family.miles_to_margaret").
+func CompileExpr(expr string) (*Expr, error) {
+	tokens, err := lexExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", expr, err)
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", expr, err)
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("expr %q: unexpected token %q", expr, tok.text)
+	}
+	return &Expr{src: expr, node: node}, nil
+}
+
+// Eval evaluates the compiled expression against city. Returns
+// ErrFieldMissing if a dotted field path doesn't resolve to anything on
+// City.
+func (e *Expr) Eval(city City) (float64, error) {
+	val, err := e.node.eval(city)
+	if err != nil {
+		return 0, fmt.Errorf("expr %q: %w", e.src, err)
+	}
+	return val, nil
+}
+
+// EvalExpr compiles and evaluates expr in one step. Prefer CompileExpr
+// when evaluating the same expr repeatedly (e.g. once per city), so it's
+// only lexed and parsed once.
+func EvalExpr(expr string, city City) (float64, error) {
+	compiled, err := CompileExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	return compiled.Eval(city)
+}
+
+// exprNode is a node of a compiled expression's AST.
+type exprNode interface {
+	eval(city City) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(City) (float64, error) {
+	return float64(n), nil
+}
+
+type identNode string
+
+func (n identNode) eval(city City) (float64, error) {
+	name := string(n)
+	if val, ok := exprConstants[name]; ok {
+		return val, nil
+	}
+	return resolveFieldPath(reflect.ValueOf(city), strings.Split(name, "."))
+}
+
+type unaryMinusNode struct {
+	operand exprNode
+}
+
+func (n unaryMinusNode) eval(city City) (float64, error) {
+	val, err := n.operand.eval(city)
+	return -val, err
+}
+
+type binaryNode struct {
+	op       byte // '+', '-', '*', or '/'
+	lhs, rhs exprNode
+}
+
+func (n binaryNode) eval(city City) (float64, error) {
+	lhs, err := n.lhs.eval(city)
+	if err != nil {
+		return 0, err
+	}
+	rhs, err := n.rhs.eval(city)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return lhs + rhs, nil
+	case '-':
+		return lhs - rhs, nil
+	case '*':
+		return lhs * rhs, nil
+	default: // '/'
+		return lhs / rhs, nil
+	}
+}
+
+type absCallNode struct {
+	arg exprNode
+}
+
+func (n absCallNode) eval(city City) (float64, error) {
+	val, err := n.arg.eval(city)
+	if err != nil {
+		return 0, err
+	}
+	if val < 0 {
+		return -val, nil
+	}
+	return val, nil
+}
+
+// exprConstants are identifiers that resolve to a fixed value rather than
+// a City field.
+var exprConstants = map[string]float64{
+	"usaverage": USAverageRainfall,
+}
+
+type exprTokenKind int
+
+const (
+	tokNumber exprTokenKind = iota
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokEOF
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+func lexExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, exprToken{tokLParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, exprToken{tokRParen, ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, exprToken{tokComma, ","})
+			i++
+		case c == '+' || c == '-' || c == '*' || c == '/':
+			tokens = append(tokens, exprToken{tokOp, string(c)})
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokNumber, s[i:j]})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(s) && isIdentPart(s[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokIdent, s[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", string(c))
+		}
+	}
+	tokens = append(tokens, exprToken{tokEOF, ""})
+	return tokens, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '.'
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok
+}
+
+// parseExpr handles + and - at the lowest precedence.
+func (p *exprParser) parseExpr() (exprNode, error) {
+	node, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp || (tok.text != "+" && tok.text != "-") {
+			return node, nil
+		}
+		p.next()
+		rhs, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: tok.text[0], lhs: node, rhs: rhs}
+	}
+}
+
+// parseTerm handles * and /.
+func (p *exprParser) parseTerm() (exprNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp || (tok.text != "*" && tok.text != "/") {
+			return node, nil
+		}
+		p.next()
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: tok.text[0], lhs: node, rhs: rhs}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryMinusNode{operand}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *exprParser) parseAtom() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		val, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return numberNode(val), nil
+	case tokLParen:
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, fmt.Errorf("missing closing paren")
+		}
+		p.next()
+		return node, nil
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok.text)
+		}
+		return identNode(tok.text), nil
+	default:
+		return nil, fmt.Errorf("unexpected token %q", tok.text)
+	}
+}
+
+func (p *exprParser) parseCall(name string) (exprNode, error) {
+	p.next() // consume '('
+	arg, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokRParen {
+		return nil, fmt.Errorf("missing closing paren in call to %s", name)
+	}
+	p.next()
+	switch name {
+	case "abs":
+		return absCallNode{arg}, nil
+	default:
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+}
+
+// ErrFieldMissing means a dotted field path in an expr doesn't resolve to
+// anything on City — as opposed to a syntax error in the expr itself.
+// BuildScoreSet treats this as a missing value (ok=false) rather than a
+// fatal config error.
+type ErrFieldMissing struct {
+	Path string
+}
+
+func (e ErrFieldMissing) Error() string {
+	return fmt.Sprintf("no such field %q", e.Path)
+}
+
+// resolveFieldPath walks v's struct fields, matching each path segment
+// against the field's json tag (case-insensitive), and returns the final
+// value as a float64.
+func resolveFieldPath(v reflect.Value, path []string) (float64, error) {
+	if len(path) == 0 {
+		return asFloat64(v)
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, ErrFieldMissing{path[0]}
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(tag, path[0]) {
+			return resolveFieldPath(v.Field(i), path[1:])
+		}
+	}
+	return 0, ErrFieldMissing{path[0]}
+}
+
+func asFloat64(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	default:
+		return 0, fmt.Errorf("field of kind %s is not numeric", v.Kind())
+	}
+}
+
+// addToFieldPath walks v's struct fields the same way resolveFieldPath
+// does, but adds delta to the final numeric field in place. v must be
+// addressable (e.g. reflect.ValueOf(&city).Elem()). Used by MonteCarlo to
+// perturb a city's fields by dotted path.
+func addToFieldPath(v reflect.Value, path []string, delta float64) error {
+	if len(path) == 0 {
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(v.Float() + delta)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(v.Int() + int64(math.Round(delta)))
+			return nil
+		default:
+			return fmt.Errorf("field of kind %s is not numeric", v.Kind())
+		}
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot descend into %s at %q", v.Kind(), path[0])
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(tag, path[0]) {
+			return addToFieldPath(v.Field(i), path[1:], delta)
+		}
+	}
+	return fmt.Errorf("no field %q on %s", path[0], t.Name())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEvalExpr(t *testing.T) {
+	city := City{
+		Crime: Crime{Violent: 10, Property: 20},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want float64
+	}{
+		{"literal", "42", 42},
+		{"unary minus", "-5", -5},
+		{"precedence", "2 + 3 * 4", 14},
+		{"parens", "(2 + 3) * 4", 20},
+		{"field path", "crime.violent", 10},
+		{"field path arithmetic", "0.3*crime.violent + 0.7*crime.property", 0.3*10 + 0.7*20},
+		{"abs positive", "abs(5)", 5},
+		{"abs negative", "abs(-5)", 5},
+		{"constant", "usaverage", USAverageRainfall},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalExpr(tt.expr, city)
+			if err != nil {
+				t.Fatalf("EvalExpr(%q) returned error: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("EvalExpr(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalExprUnknownField(t *testing.T) {
+	_, err := EvalExpr("crime.nosuchfield", City{})
+	if err == nil {
+		t.Fatal("EvalExpr with unknown field returned no error")
+	}
+	var missing ErrFieldMissing
+	if !errors.As(err, &missing) {
+		t.Errorf("EvalExpr with unknown field returned %v, want an ErrFieldMissing", err)
+	}
+}
+
+func TestEvalExprSyntaxError(t *testing.T) {
+	_, err := EvalExpr("1 +", City{})
+	if err == nil {
+		t.Fatal("EvalExpr with malformed syntax returned no error")
+	}
+	var missing ErrFieldMissing
+	if errors.As(err, &missing) {
+		t.Errorf("EvalExpr with malformed syntax returned ErrFieldMissing, want a syntax error")
+	}
+}
+
+func TestEvalExprNaN(t *testing.T) {
+	got, err := EvalExpr("0/0", City{})
+	if err != nil {
+		t.Fatalf("EvalExpr(\"0/0\") returned error: %v", err)
+	}
+	if !math.IsNaN(got) {
+		t.Errorf("EvalExpr(\"0/0\") = %v, want NaN", got)
+	}
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"math"
 	"sort"
+	"time"
 
 	"github.com/ghodss/yaml"
+	"golang.org/x/exp/rand"
 	"gonum.org/v1/gonum/mat"
-	"gonum.org/v1/gonum/stat"
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
@@ -22,6 +24,19 @@ type Input struct {
 	AnnualIncome    int     `json:"annual_income"`
 	HomeEquity      float64 `json:"home_equity"`
 	CandidateCities []City  `json:"candidate_cities"`
+
+	// Correlations declares pairs of a city's Uncertainty fields that
+	// should be resampled together rather than independently (e.g. a
+	// state's property and income tax rates tend to move together). See
+	// MonteCarlo.
+	Correlations []Correlation `json:"correlations,omitempty"`
+}
+
+// Correlation ties two of a city's Uncertainty fields together with
+// correlation coefficient Rho, for MonteCarlo's covariance matrix.
+type Correlation struct {
+	Fields [2]string `json:"fields"`
+	Rho    float64   `json:"rho"`
 }
 
 type City struct {
@@ -33,6 +48,12 @@ type City struct {
 	Climate    Climate    `json:"climate"`
 	Family     Family     `json:"family"`
 	Livability Livability `json:"livability"`
+
+	// Uncertainty maps a dotted field path, the same kind scoring.yaml's
+	// expr uses (e.g. "real_estate.market"), to that field's standard
+	// deviation. Fields with no entry here are treated as exact. See
+	// MonteCarlo.
+	Uncertainty map[string]float64 `json:"uncertainty,omitempty"`
 }
 
 type HighSchool struct {
@@ -91,7 +112,9 @@ type ScoreSet struct {
 	scores      *mat.Dense
 }
 
-type ScoreFunc func(City) float64
+// ScoreFunc computes a raw score for a City, or returns ok=false if the
+// value is missing (e.g. the city has no data for that axis).
+type ScoreFunc func(City) (value float64, ok bool)
 
 type ScoreGoal int
 
@@ -100,7 +123,14 @@ const (
 	SMALLER
 )
 
+// NewScoreSet builds a single-axis ScoreSet, normalized with ZScore. Use
+// NewScoreSetWith to pick a different Normalizer (e.g. RobustZ for
+// distance-like axes with outliers, RankNormal for small-N axes).
 func NewScoreSet(axis string, cities []City, better ScoreGoal, f ScoreFunc) *ScoreSet {
+	return NewScoreSetWith(axis, cities, better, ZScore{}, f)
+}
+
+func NewScoreSetWith(axis string, cities []City, better ScoreGoal, norm Normalizer, f ScoreFunc) *ScoreSet {
 	var result ScoreSet
 	result.columnNames = make([]string, len(cities))
 	result.rowNames = []string{axis}
@@ -109,19 +139,16 @@ func NewScoreSet(axis string, cities []City, better ScoreGoal, f ScoreFunc) *Sco
 	row := make([]float64, len(cities))
 	for i, city := range cities {
 		result.columnNames[i] = city.Name
-		row[i] = f(city)
-	}
-
-	// z-score normalize the row
-	mu, sigma := stat.MeanStdDev(row, nil)
-	for i := range row {
-		val := (row[i] - mu) / sigma
-		if SMALLER == better {
-			val = val * -1.0
+		val, ok := f(city)
+		if !ok {
+			row[i] = math.NaN()
+			continue
 		}
 		row[i] = val
 	}
 
+	row = norm.Normalize(row, better)
+
 	result.scores = mat.NewDense(1, len(cities), row)
 	return &result
 }
@@ -144,6 +171,10 @@ func (set *ScoreSet) Print() {
 		scored := make([]scoredCity, len(set.columnNames))
 		for j, cityName := range set.columnNames {
 			scored[j].Name = cityName
+			if math.IsNaN(row[j]) {
+				scored[j].Score = math.NaN()
+				continue
+			}
 			scored[j].Score = 100.0 * distuv.UnitNormal.CDF(row[j])
 		}
 
@@ -151,6 +182,10 @@ func (set *ScoreSet) Print() {
 			return scored[i].Score > scored[j].Score
 		})
 		for j := range scored {
+			if math.IsNaN(scored[j].Score) {
+				log.Printf("    %-20s%5s", scored[j].Name, "N/A")
+				continue
+			}
 			percent = fmt.Sprintf("%0.1f", scored[j].Score)
 			log.Printf("    %-20s%4s%%", scored[j].Name, percent)
 		}
@@ -158,19 +193,20 @@ func (set *ScoreSet) Print() {
 
 	log.Print("Final")
 	{
+		means := computeColumnMeans(set.scores, set.rowWeights)
 		scored := make([]scoredCity, len(set.columnNames))
 		for j, cityName := range set.columnNames {
-			col := mat.Col(nil, j, set.scores)
-
-			mu, _ := stat.MeanStdDev(col, set.rowWeights)
-
 			scored[j].Name = cityName
-			scored[j].Score = 100.0 * distuv.UnitNormal.CDF(mu)
+			scored[j].Score = 100.0 * distuv.UnitNormal.CDF(means[j])
 		}
 		sort.Slice(scored, func(i, j int) bool {
 			return scored[i].Score > scored[j].Score
 		})
 		for j := range scored {
+			if math.IsNaN(scored[j].Score) {
+				log.Printf("    %-20s%5s", scored[j].Name, "N/A")
+				continue
+			}
 			percent := fmt.Sprintf("%0.1f", scored[j].Score)
 			log.Printf("    %-20s%4s%%", scored[j].Name, percent)
 		}
@@ -178,6 +214,37 @@ func (set *ScoreSet) Print() {
 
 }
 
+// weightedMeanIgnoringMissing computes a weighted mean of values, skipping
+// NaN (missing) entries and renormalizing the remaining weights so they
+// still sum to 1. Rows that are missing for every city (or an entirely
+// missing column) contribute nothing rather than poisoning the result.
+func weightedMeanIgnoringMissing(values []float64, weights []float64) float64 {
+	var sumWeight, sumWeightedValue float64
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		sumWeight += weights[i]
+		sumWeightedValue += weights[i] * v
+	}
+	if sumWeight == 0 {
+		return math.NaN()
+	}
+	return sumWeightedValue / sumWeight
+}
+
+// computeColumnMeans computes the weighted mean of each column (city) of
+// scores against weights, via weightedMeanIgnoringMissing. Shared by
+// Print and Sensitivity so the two can't drift apart.
+func computeColumnMeans(scores *mat.Dense, weights []float64) []float64 {
+	_, cols := scores.Dims()
+	means := make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		means[j] = weightedMeanIgnoringMissing(mat.Col(nil, j, scores), weights)
+	}
+	return means
+}
+
 func Merge(sets []*ScoreSet, weights []float64) *ScoreSet {
 	if len(sets) < 2 {
 		panic("Merge must have 2 or more ScoreSets")
@@ -190,8 +257,9 @@ func Merge(sets []*ScoreSet, weights []float64) *ScoreSet {
 		for _, weight := range weights {
 			total += weight
 		}
-		if total != 1.0 {
-			panic("merge weights must sum to 1.0")
+		const weightTolerance = 1e-9
+		if math.Abs(total-1.0) > weightTolerance {
+			panic(fmt.Sprintf("merge weights must sum to 1.0, got %v", total))
 		}
 	}
 
@@ -239,20 +307,20 @@ func Merge(sets []*ScoreSet, weights []float64) *ScoreSet {
 // func NewNormal(mu []float64, sigma mat.Symmetric, src rand.Source) (*Normal, bool)
 
 func ScoreEducation(input Input) *ScoreSet {
-	usnews := NewScoreSet("/Education/USNews", input.CandidateCities, BIGGER, func(city City) float64 {
-		return float64(20000 - city.Education.USNews)
+	usnews := NewScoreSet("/Education/USNews", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return float64(20000 - city.Education.USNews), true
 	})
-	math := NewScoreSet("/Education/Math", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Education.MathProficiency
+	math := NewScoreSet("/Education/Math", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Education.MathProficiency, true
 	})
-	reading := NewScoreSet("/Education/Reading", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Education.ReadingProficiency
+	reading := NewScoreSet("/Education/Reading", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Education.ReadingProficiency, true
 	})
-	graduation := NewScoreSet("/Education/Graduation", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Education.GraduationRate
+	graduation := NewScoreSet("/Education/Graduation", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Education.GraduationRate, true
 	})
-	college := NewScoreSet("/Education/CollegeReadiness", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Education.CollegeReadiness
+	college := NewScoreSet("/Education/CollegeReadiness", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Education.CollegeReadiness, true
 	})
 
 	myscore := Merge([]*ScoreSet{
@@ -284,7 +352,7 @@ func mortgage(P float64) float64 {
 }
 
 func ScoreFinancial(input Input) *ScoreSet {
-	return NewScoreSet("/Financial", input.CandidateCities, SMALLER, func(city City) float64 {
+	return NewScoreSet("/Financial", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
 		loan := float64(city.RealEstate.MarketValue) - input.HomeEquity
 
 		annual_income_tax := float64(input.AnnualIncome) * city.Taxes.Income
@@ -298,25 +366,25 @@ func ScoreFinancial(input Input) *ScoreSet {
 			annual_expenses +
 			annual_property_taxes
 
-		return score
+		return score, true
 	})
 }
 
 func ScoreClimate(input Input) *ScoreSet {
-	sunshine := NewScoreSet("/Livability/Climate/Sunshine", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Climate.SunnyDays
+	sunshine := NewScoreSet("/Livability/Climate/Sunshine", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Climate.SunnyDays, true
 	})
 
-	snow := NewScoreSet("/Livability/Climate/Snow", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Climate.SnowInches
+	snow := NewScoreSet("/Livability/Climate/Snow", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Climate.SnowInches, true
 	})
 
-	precip := NewScoreSet("/Livability/Climate/Precip", input.CandidateCities, SMALLER, func(city City) float64 {
+	precip := NewScoreSet("/Livability/Climate/Precip", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
 		score := USAverageRainfall - (city.Climate.RainInches + city.Climate.SnowInches)
 		if score < 0.0 {
 			score = -1.0 * score
 		}
-		return score
+		return score, true
 	})
 
 	return Merge([]*ScoreSet{
@@ -331,20 +399,20 @@ func ScoreClimate(input Input) *ScoreSet {
 }
 
 func ScoreFamily(input Input) *ScoreSet {
-	margaret := NewScoreSet("/Family/Margaret", input.CandidateCities, SMALLER, func(city City) float64 {
-		return city.Family.MilesToMargaret
+	margaret := NewScoreSet("/Family/Margaret", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
+		return city.Family.MilesToMargaret, true
 	})
 
-	nich := NewScoreSet("/Family/Nich", input.CandidateCities, SMALLER, func(city City) float64 {
-		return city.Family.MilesToNich
+	nich := NewScoreSet("/Family/Nich", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
+		return city.Family.MilesToNich, true
 	})
 
-	peggy := NewScoreSet("/Family/Peggy", input.CandidateCities, SMALLER, func(city City) float64 {
-		return city.Family.MilesToPeggy
+	peggy := NewScoreSet("/Family/Peggy", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
+		return city.Family.MilesToPeggy, true
 	})
 
-	ryan := NewScoreSet("/Family/Ryan", input.CandidateCities, SMALLER, func(city City) float64 {
-		return city.Family.MilesToRyan
+	ryan := NewScoreSet("/Family/Ryan", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
+		return city.Family.MilesToRyan, true
 	})
 
 	return Merge([]*ScoreSet{
@@ -361,30 +429,30 @@ func ScoreFamily(input Input) *ScoreSet {
 }
 
 func ScoreLivability(input Input) *ScoreSet {
-	crime := NewScoreSet("/Livability/Crime", input.CandidateCities, SMALLER, func(city City) float64 {
-		return (0.3 * city.Crime.Violent) + (0.7 * city.Crime.Property)
+	crime := NewScoreSet("/Livability/Crime", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
+		return (0.3 * city.Crime.Violent) + (0.7 * city.Crime.Property), true
 	})
 
-	politics := NewScoreSet("/Livability/Politics", input.CandidateCities, BIGGER, func(city City) float64 {
-		return 1.0 - math.Abs(0.70-city.Livability.Politics)
+	politics := NewScoreSet("/Livability/Politics", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return 1.0 - math.Abs(0.70-city.Livability.Politics), true
 	})
 
 	climate := ScoreClimate(input)
 
-	culture := NewScoreSet("/Livability/Culture", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Livability.Culture
+	culture := NewScoreSet("/Livability/Culture", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Livability.Culture, true
 	})
 
-	running := NewScoreSet("/Livability/Running", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Livability.Running
+	running := NewScoreSet("/Livability/Running", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Livability.Running, true
 	})
 
-	walkscore := NewScoreSet("/Livability/WalkScore", input.CandidateCities, BIGGER, func(city City) float64 {
-		return city.Livability.WalkScore
+	walkscore := NewScoreSet("/Livability/WalkScore", input.CandidateCities, BIGGER, func(city City) (float64, bool) {
+		return city.Livability.WalkScore, true
 	})
 
-	airport := NewScoreSet("/Livability/Airport", input.CandidateCities, SMALLER, func(city City) float64 {
-		return city.Livability.MilesToAirport
+	airport := NewScoreSet("/Livability/Airport", input.CandidateCities, SMALLER, func(city City) (float64, bool) {
+		return city.Livability.MilesToAirport, true
 	})
 
 	return Merge([]*ScoreSet{
@@ -403,7 +471,51 @@ func ScoreLivability(input Input) *ScoreSet {
 	})
 }
 
+// buildScoreSets assembles the top-level dimensions to merge into the
+// final ranking. If cfg is non-nil, the Education/Family/Livability trees
+// are built from it, which lets weights and axes be retuned without
+// recompiling. Financial always comes from ScoreFinancial since it needs
+// Input-level fields (income, expenses, home equity) that scoring.yaml's
+// per-City expressions can't see. With cfg nil, everything falls back to
+// the built-in Score* functions. cfg is loaded once by the caller (see
+// main and MonteCarlo) rather than per call, since MonteCarlo calls this
+// once per draw.
+func buildScoreSets(cfg *ScoringConfig, input Input) ([]*ScoreSet, []float64, error) {
+	if cfg == nil {
+		return []*ScoreSet{
+				ScoreEducation(input),
+				ScoreFamily(input),
+				ScoreFinancial(input),
+				ScoreLivability(input),
+			}, []float64{
+				0.33,
+				0.33,
+				0.17,
+				0.17,
+			}, nil
+	}
+
+	sets := make([]*ScoreSet, 0, len(cfg.Dimensions)+1)
+	weights := make([]float64, 0, len(cfg.Dimensions)+1)
+	for _, dim := range cfg.Dimensions {
+		set, err := BuildScoreSet(dim, input.CandidateCities)
+		if err != nil {
+			return nil, nil, err
+		}
+		sets = append(sets, set)
+		weights = append(weights, dim.Weight)
+	}
+	sets = append(sets, ScoreFinancial(input))
+	weights = append(weights, cfg.FinancialWeight)
+	return sets, weights, nil
+}
+
 func main() {
+	mode := flag.String("mode", "weighted", "output mode: weighted, pareto, or both")
+	sensitivity := flag.Bool("sensitivity", false, "also print a weight-sensitivity report")
+	montecarlo := flag.Int("montecarlo", 0, "also run this many Monte Carlo draws over uncertain fields")
+	flag.Parse()
+
 	data, err := ioutil.ReadFile("data.yaml")
 	if err != nil {
 		log.Fatal(err)
@@ -414,27 +526,36 @@ func main() {
 		log.Fatal(err)
 	}
 
-	education := ScoreEducation(input)
-	financial := ScoreFinancial(input)
-	family := ScoreFamily(input)
-	livability := ScoreLivability(input)
+	cfg, err := LoadScoringConfig("scoring.yaml")
+	if err != nil {
+		log.Printf("scoring.yaml not found (%v); using built-in scoring", err)
+		cfg = nil
+	}
 
-	merged := Merge([]*ScoreSet{
-		education,
-		family,
-		financial,
-		livability,
-	}, []float64{
-		0.33,
-		0.33,
-		0.17,
-		0.17,
-	})
+	sets, weights, err := buildScoreSets(cfg, input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	merged := Merge(sets, weights)
+
+	switch *mode {
+	case "weighted":
+		merged.Print()
+	case "pareto":
+		merged.PrintPareto()
+	case "both":
+		merged.Print()
+		merged.PrintPareto()
+	default:
+		log.Fatalf("unknown -mode %q: want weighted, pareto, or both", *mode)
+	}
 
-	if false {
-		panic(merged)
+	if *sensitivity {
+		merged.Sensitivity(0.01).Print()
 	}
 
-	merged.Print()
-	//PrintScores("Final", merged)
+	if *montecarlo > 0 {
+		src := rand.NewSource(uint64(time.Now().UnixNano()))
+		MonteCarlo(input, cfg, *montecarlo, src).Print()
+	}
 }
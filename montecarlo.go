@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distmv"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// MCCityResult summarizes one city's final score across Monte Carlo draws.
+type MCCityResult struct {
+	Name      string
+	Mean      float64
+	P5, P50   float64
+	P95       float64
+	ProbFirst float64 // fraction of draws in which this city ranked #1
+}
+
+// MCReport is the result of MonteCarlo, sorted by Mean descending.
+type MCReport struct {
+	Cities []MCCityResult
+}
+
+// MonteCarlo reruns the whole scoring pipeline n times, each time
+// resampling every city's Uncertainty fields from a Gaussian (correlated
+// per input.Correlations, independent otherwise), and reports each city's
+// mean final score, 5/50/95 percentiles, and P(city ranks #1). This gives
+// "Austin beats Denver 62% of the time" instead of a false-precision
+// single number.
+//
+// MonteCarlo isn't a ScoreSet method since a ScoreSet only holds already
+// z-scored axes with no path back to the raw City fields that need
+// resampling each draw; it operates on Input and rebuilds the ScoreSet
+// tree (via buildScoreSets, same as main) on every draw. cfg is parsed
+// once by the caller and reused for every draw rather than reloaded from
+// disk n times.
+func MonteCarlo(input Input, cfg *ScoringConfig, n int, src rand.Source) MCReport {
+	scores := make([][]float64, len(input.CandidateCities))
+	firstCount := make([]int, len(input.CandidateCities))
+
+	for d := 0; d < n; d++ {
+		sample := resampleInput(input, src)
+		sets, weights, err := buildScoreSets(cfg, sample)
+		if err != nil {
+			log.Fatal(err)
+		}
+		merged := Merge(sets, weights)
+		means := computeColumnMeans(merged.scores, merged.rowWeights)
+
+		best, bestScore := 0, math.Inf(-1)
+		for i, mean := range means {
+			score := 100.0 * distuv.UnitNormal.CDF(mean)
+			scores[i] = append(scores[i], score)
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		firstCount[best]++
+	}
+
+	results := make([]MCCityResult, len(input.CandidateCities))
+	for i, city := range input.CandidateCities {
+		draws := scores[i]
+		sort.Float64s(draws)
+		results[i] = MCCityResult{
+			Name:      city.Name,
+			Mean:      stat.Mean(draws, nil),
+			P5:        stat.Quantile(0.05, stat.Empirical, draws, nil),
+			P50:       stat.Quantile(0.50, stat.Empirical, draws, nil),
+			P95:       stat.Quantile(0.95, stat.Empirical, draws, nil),
+			ProbFirst: float64(firstCount[i]) / float64(n),
+		}
+	}
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].Mean > results[b].Mean
+	})
+	return MCReport{Cities: results}
+}
+
+// resampleInput returns a copy of input with each city's Uncertainty
+// fields resampled from a multivariate normal centered on their current
+// value: diagonal (independent) unless input.Correlations ties two of a
+// city's fields together, in which case their draws are correlated.
+func resampleInput(input Input, src rand.Source) Input {
+	sample := input
+	sample.CandidateCities = make([]City, len(input.CandidateCities))
+	copy(sample.CandidateCities, input.CandidateCities)
+
+	for ci := range sample.CandidateCities {
+		city := &sample.CandidateCities[ci]
+		if len(city.Uncertainty) == 0 {
+			continue
+		}
+
+		paths := make([]string, 0, len(city.Uncertainty))
+		for path := range city.Uncertainty {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		sigma := mat.NewSymDense(len(paths), nil)
+		for i, path := range paths {
+			sd := city.Uncertainty[path]
+			sigma.SetSym(i, i, sd*sd)
+		}
+		for _, corr := range input.Correlations {
+			i, j := indexOf(paths, corr.Fields[0]), indexOf(paths, corr.Fields[1])
+			if i < 0 || j < 0 {
+				continue
+			}
+			cov := corr.Rho * city.Uncertainty[paths[i]] * city.Uncertainty[paths[j]]
+			sigma.SetSym(i, j, cov)
+		}
+
+		mu := make([]float64, len(paths))
+		normal, ok := distmv.NewNormal(mu, sigma, src)
+		if !ok {
+			continue // sigma isn't positive-definite; leave this city unperturbed
+		}
+		deltas := normal.Rand(nil)
+
+		v := reflect.ValueOf(city).Elem()
+		for i, path := range paths {
+			_ = addToFieldPath(v, strings.Split(path, "."), deltas[i])
+		}
+	}
+
+	return sample
+}
+
+// Print logs each city's Monte Carlo summary, best mean first.
+func (r MCReport) Print() {
+	log.Print("Monte Carlo")
+	for _, c := range r.Cities {
+		log.Printf("    %-20s mean=%5.1f%%  p5=%5.1f%%  p50=%5.1f%%  p95=%5.1f%%  P(#1)=%4.1f%%",
+			c.Name, c.Mean, c.P5, c.P50, c.P95, 100.0*c.ProbFirst)
+	}
+}
+
+func indexOf(paths []string, path string) int {
+	for i, p := range paths {
+		if p == path {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Normalizer converts a raw row of per-city values (NaN for missing) into
+// z-score-like values: roughly centered on 0, with the sign already
+// flipped so bigger is always better, ready for the distuv.UnitNormal.CDF
+// step in Print. Missing (NaN) entries pass through untouched.
+type Normalizer interface {
+	Normalize(row []float64, better ScoreGoal) []float64
+}
+
+// ZScore is the original normalization: (x - mean) / stddev, ignoring
+// missing entries, with a neutral 0 when the axis is flat (sigma==0) or
+// there's only one present value (stat.MeanStdDev's n=1 stddev is NaN).
+// Good default for roughly-normal data with no extreme outliers.
+type ZScore struct{}
+
+func (ZScore) Normalize(row []float64, better ScoreGoal) []float64 {
+	present := presentValues(row)
+	if len(present) == 0 {
+		return allNaN(row)
+	}
+	mu, sigma := stat.MeanStdDev(present, nil)
+	return mapPresent(row, func(v float64) float64 {
+		if sigma == 0 || math.IsNaN(sigma) {
+			return 0
+		}
+		z := (v - mu) / sigma
+		return flip(z, better)
+	})
+}
+
+// RobustZ normalizes using the median and median absolute deviation (MAD)
+// instead of mean/stddev, so a single outlier (one city 200 miles further
+// from family than everyone else) doesn't distort every other city's
+// score. Good for axes like distances that can have long tails.
+type RobustZ struct{}
+
+func (RobustZ) Normalize(row []float64, better ScoreGoal) []float64 {
+	present := presentValues(row)
+	if len(present) == 0 {
+		return allNaN(row)
+	}
+	med := median(present)
+	deviations := make([]float64, len(present))
+	for i, v := range present {
+		deviations[i] = math.Abs(v - med)
+	}
+	mad := median(deviations) * 1.4826 // scale MAD to be consistent with stddev under normality
+	return mapPresent(row, func(v float64) float64 {
+		if mad == 0 {
+			return 0
+		}
+		z := (v - med) / mad
+		return flip(z, better)
+	})
+}
+
+// MinMax linearly rescales values to [-1, 1]. Good when you want every
+// present value to matter equally regardless of its distance from the
+// middle of the pack (no single axis can swamp the scale).
+type MinMax struct{}
+
+func (MinMax) Normalize(row []float64, better ScoreGoal) []float64 {
+	present := presentValues(row)
+	if len(present) == 0 {
+		return allNaN(row)
+	}
+	lo, hi := present[0], present[0]
+	for _, v := range present {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	span := hi - lo
+	return mapPresent(row, func(v float64) float64 {
+		if span == 0 {
+			return 0
+		}
+		scaled := 2*(v-lo)/span - 1
+		return flip(scaled, better)
+	})
+}
+
+// RankNormal converts each present value to its rank, then applies the
+// inverse normal CDF to (rank-0.5)/n, so the output is Gaussian by
+// construction and distuv.UnitNormal.CDF in Print still gives a
+// meaningful percentile. Good for small-N or categorical-ish scores
+// where the raw gaps between values aren't meaningful, only the order.
+type RankNormal struct{}
+
+func (RankNormal) Normalize(row []float64, better ScoreGoal) []float64 {
+	present := presentValues(row)
+	if len(present) == 0 {
+		return allNaN(row)
+	}
+	n := len(present)
+	sorted := append([]float64(nil), present...)
+	sort.Float64s(sorted)
+
+	rank := func(v float64) float64 {
+		i := sort.SearchFloat64s(sorted, v)
+		return float64(i) + 1
+	}
+
+	return mapPresent(row, func(v float64) float64 {
+		if n <= 1 {
+			return 0
+		}
+		p := (rank(v) - 0.5) / float64(n)
+		z := distuv.UnitNormal.Quantile(p)
+		return flip(z, better)
+	})
+}
+
+func flip(z float64, better ScoreGoal) float64 {
+	if better == SMALLER {
+		return -z
+	}
+	return z
+}
+
+// allNaN returns a row of the same length as row, entirely NaN — used when
+// an axis has no present values at all, so there's nothing to normalize
+// against.
+func allNaN(row []float64) []float64 {
+	out := make([]float64, len(row))
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+func presentValues(row []float64) []float64 {
+	present := make([]float64, 0, len(row))
+	for _, v := range row {
+		if !math.IsNaN(v) {
+			present = append(present, v)
+		}
+	}
+	return present
+}
+
+func mapPresent(row []float64, f func(float64) float64) []float64 {
+	out := make([]float64, len(row))
+	for i, v := range row {
+		if math.IsNaN(v) {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = f(v)
+	}
+	return out
+}
+
+// normalizerFor maps a scoring.yaml "norm" field to a Normalizer, with
+// ZScore as the default for an empty/unset value.
+func normalizerFor(name string) Normalizer {
+	switch name {
+	case "", "zscore":
+		return ZScore{}
+	case "robustz":
+		return RobustZ{}
+	case "minmax":
+		return MinMax{}
+	case "ranknormal":
+		return RankNormal{}
+	default:
+		log.Fatalf("scoring.yaml: unknown norm %q (want zscore, robustz, minmax, or ranknormal)", name)
+		return nil
+	}
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return math.NaN()
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
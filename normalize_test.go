@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func allPresent(t *testing.T, got []float64) {
+	t.Helper()
+	for i, v := range got {
+		if math.IsNaN(v) {
+			t.Errorf("got[%d] = NaN, want a present value", i)
+		}
+	}
+}
+
+func TestNormalizersEmptyAxis(t *testing.T) {
+	row := []float64{math.NaN(), math.NaN(), math.NaN()}
+	normalizers := []Normalizer{ZScore{}, RobustZ{}, MinMax{}, RankNormal{}}
+
+	for _, n := range normalizers {
+		t.Run(typeName(n), func(t *testing.T) {
+			got := n.Normalize(row, BIGGER)
+			if len(got) != len(row) {
+				t.Fatalf("Normalize returned %d values, want %d", len(got), len(row))
+			}
+			for i, v := range got {
+				if !math.IsNaN(v) {
+					t.Errorf("got[%d] = %v, want NaN", i, v)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizersFlatAxis(t *testing.T) {
+	row := []float64{5, 5, 5}
+	normalizers := []Normalizer{ZScore{}, RobustZ{}, MinMax{}}
+
+	for _, n := range normalizers {
+		t.Run(typeName(n), func(t *testing.T) {
+			got := n.Normalize(row, BIGGER)
+			allPresent(t, got)
+			for i, v := range got {
+				if v != 0 {
+					t.Errorf("got[%d] = %v, want 0 for a flat axis", i, v)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizersFlipsOnGoal(t *testing.T) {
+	row := []float64{1, 2, 3}
+	normalizers := []Normalizer{ZScore{}, RobustZ{}, MinMax{}, RankNormal{}}
+
+	for _, n := range normalizers {
+		t.Run(typeName(n), func(t *testing.T) {
+			bigger := n.Normalize(row, BIGGER)
+			smaller := n.Normalize(row, SMALLER)
+			for i := range row {
+				if bigger[i] != -smaller[i] {
+					t.Errorf("index %d: BIGGER=%v, SMALLER=%v, want negatives of each other", i, bigger[i], smaller[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMinMaxRange(t *testing.T) {
+	row := []float64{1, 2, 3, math.NaN()}
+	got := MinMax{}.Normalize(row, BIGGER)
+	if got[0] != -1 {
+		t.Errorf("min value got %v, want -1", got[0])
+	}
+	if got[2] != 1 {
+		t.Errorf("max value got %v, want 1", got[2])
+	}
+	if !math.IsNaN(got[3]) {
+		t.Errorf("missing value got %v, want NaN", got[3])
+	}
+}
+
+func TestNormalizersSinglePresentValue(t *testing.T) {
+	// One city has data on this axis, the rest are missing: the sole
+	// present value must get a neutral score, not NaN (stat.MeanStdDev's
+	// n=1 stddev is NaN, which ZScore must catch alongside sigma==0).
+	row := []float64{5, math.NaN(), math.NaN()}
+	normalizers := []Normalizer{ZScore{}, RobustZ{}, MinMax{}, RankNormal{}}
+
+	for _, n := range normalizers {
+		t.Run(typeName(n), func(t *testing.T) {
+			got := n.Normalize(row, BIGGER)
+			if math.IsNaN(got[0]) {
+				t.Errorf("got[0] = NaN, want a neutral score for the single present value")
+			}
+			if got[0] != 0 {
+				t.Errorf("got[0] = %v, want 0", got[0])
+			}
+		})
+	}
+}
+
+func typeName(n Normalizer) string {
+	switch n.(type) {
+	case ZScore:
+		return "ZScore"
+	case RobustZ:
+		return "RobustZ"
+	case MinMax:
+		return "MinMax"
+	case RankNormal:
+		return "RankNormal"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ParetoFrontier is one non-dominated layer of cities: no member of a
+// frontier is beaten on every axis by another member of the same
+// frontier. Frontier 1 is the overall non-dominated set.
+type ParetoFrontier struct {
+	Rank    int
+	Members []ParetoMember
+}
+
+// ParetoMember is one city within a frontier, carrying its crowding
+// distance: the sum, over axes, of the normalized gap to its neighbors
+// when the frontier is sorted along that axis. A larger crowding distance
+// means the city represents a more distinctive tradeoff within its
+// frontier; boundary cities (best or worst on some axis) get +Inf.
+type ParetoMember struct {
+	Name     string
+	Crowding float64
+}
+
+// dominates reports whether city a's scores are >= city b's on every axis
+// and strictly greater on at least one. Axes where either city is missing
+// (NaN) are skipped for both.
+func dominates(scores *mat.Dense, a, b int) bool {
+	rows, _ := scores.Dims()
+	strictlyBetter := false
+	for r := 0; r < rows; r++ {
+		va, vb := scores.At(r, a), scores.At(r, b)
+		if math.IsNaN(va) || math.IsNaN(vb) {
+			continue
+		}
+		if va < vb {
+			return false
+		}
+		if va > vb {
+			strictlyBetter = true
+		}
+	}
+	return strictlyBetter
+}
+
+// Pareto computes successive non-dominated frontiers across the cities
+// (NSGA-II style non-dominated sort): take the non-dominated set, remove
+// it, repeat on what's left. This surfaces tradeoffs that a single
+// weighted mean averages away — a city that's best on schools and worst
+// on cost sits in frontier 1 next to a city that's merely "pretty good"
+// on both.
+func (set *ScoreSet) Pareto() []ParetoFrontier {
+	remaining := make([]int, len(set.columnNames))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	var frontiers []ParetoFrontier
+	rank := 1
+	for len(remaining) > 0 {
+		var frontier []int
+		for _, i := range remaining {
+			dominated := false
+			for _, j := range remaining {
+				if i != j && dominates(set.scores, j, i) {
+					dominated = true
+					break
+				}
+			}
+			if !dominated {
+				frontier = append(frontier, i)
+			}
+		}
+
+		crowd := crowdingDistance(set.scores, frontier)
+		members := make([]ParetoMember, len(frontier))
+		for k, i := range frontier {
+			members[k] = ParetoMember{Name: set.columnNames[i], Crowding: crowd[i]}
+		}
+		sort.Slice(members, func(a, b int) bool {
+			return members[a].Crowding > members[b].Crowding
+		})
+		frontiers = append(frontiers, ParetoFrontier{Rank: rank, Members: members})
+
+		frontierSet := make(map[int]bool, len(frontier))
+		for _, i := range frontier {
+			frontierSet[i] = true
+		}
+		next := remaining[:0]
+		for _, i := range remaining {
+			if !frontierSet[i] {
+				next = append(next, i)
+			}
+		}
+		remaining = next
+		rank++
+	}
+	return frontiers
+}
+
+// crowdingDistance computes the NSGA-II crowding distance of each city in
+// indices: for every axis, sort the cities along it and add each city's
+// normalized gap to its neighbors. Boundary cities (the best or worst on
+// some axis) get +Inf so they're never squeezed out as redundant.
+func crowdingDistance(scores *mat.Dense, indices []int) map[int]float64 {
+	dist := make(map[int]float64, len(indices))
+	for _, i := range indices {
+		dist[i] = 0
+	}
+	if len(indices) <= 2 {
+		for _, i := range indices {
+			dist[i] = math.Inf(1)
+		}
+		return dist
+	}
+
+	rows, _ := scores.Dims()
+	sorted := make([]int, len(indices))
+	for r := 0; r < rows; r++ {
+		copy(sorted, indices)
+		sort.Slice(sorted, func(a, b int) bool {
+			return scores.At(r, sorted[a]) < scores.At(r, sorted[b])
+		})
+
+		lo, hi := scores.At(r, sorted[0]), scores.At(r, sorted[len(sorted)-1])
+		if math.IsNaN(lo) || math.IsNaN(hi) {
+			continue
+		}
+		span := hi - lo
+
+		dist[sorted[0]] = math.Inf(1)
+		dist[sorted[len(sorted)-1]] = math.Inf(1)
+		if span == 0 {
+			continue
+		}
+		for k := 1; k < len(sorted)-1; k++ {
+			gap := scores.At(r, sorted[k+1]) - scores.At(r, sorted[k-1])
+			if math.IsNaN(gap) {
+				continue
+			}
+			dist[sorted[k]] += gap / span
+		}
+	}
+	return dist
+}
+
+// PrintPareto logs the Pareto frontiers, most-preferred first, each city
+// annotated with its crowding distance (or "boundary" at +Inf).
+func (set *ScoreSet) PrintPareto() {
+	for _, f := range set.Pareto() {
+		log.Printf("Frontier %d", f.Rank)
+		for _, m := range f.Members {
+			if math.IsInf(m.Crowding, 1) {
+				log.Printf("    %-20s  (boundary)", m.Name)
+			} else {
+				log.Printf("    %-20s  crowding=%.3f", m.Name, m.Crowding)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+)
+
+// SensitivityReport shows how fragile the final ranking is with respect
+// to the hand-picked rowWeights: a per-axis, per-city partial derivative
+// of the final score, plus the smallest single-weight perturbation that
+// flips the top-1 or top-3 cities.
+type SensitivityReport struct {
+	// Gradient[axis][city] is d(final z-score)/d(weight[axis]), estimated
+	// by perturbing that weight and renormalizing the rest proportionally.
+	Gradient map[string]map[string]float64
+	// MinPerturbTop1 is the smallest |delta| to any single weight that
+	// changes which city ranks #1. +Inf if no probed delta did.
+	MinPerturbTop1 float64
+	// MinPerturbTop3 is the smallest |delta| to any single weight that
+	// changes the top-3 cities or their order. +Inf if no probed delta did.
+	MinPerturbTop3 float64
+}
+
+// Sensitivity perturbs each rowWeight by ±perturb (renormalizing the
+// others proportionally so they still sum to 1), recomputes the final
+// column means via computeColumnMeans, and reports the resulting
+// per-weight gradient and ranking stability. This reuses the same mean
+// computation as Print rather than duplicating the Merge math.
+func (set *ScoreSet) Sensitivity(perturb float64) SensitivityReport {
+	base := computeColumnMeans(set.scores, set.rowWeights)
+	baseRanking := rankCities(set.columnNames, base)
+
+	report := SensitivityReport{
+		Gradient:       make(map[string]map[string]float64, len(set.rowNames)),
+		MinPerturbTop1: math.Inf(1),
+		MinPerturbTop3: math.Inf(1),
+	}
+
+	for axisIdx, axis := range set.rowNames {
+		report.Gradient[axis] = make(map[string]float64, len(set.columnNames))
+
+		plusMeans := computeColumnMeans(set.scores, perturbWeights(set.rowWeights, axisIdx, perturb))
+		for j, cityName := range set.columnNames {
+			report.Gradient[axis][cityName] = (plusMeans[j] - base[j]) / perturb
+		}
+
+		for _, delta := range sensitivityProbeDeltas(perturb) {
+			for _, sign := range [2]float64{1, -1} {
+				w := perturbWeights(set.rowWeights, axisIdx, sign*delta)
+				ranking := rankCities(set.columnNames, computeColumnMeans(set.scores, w))
+
+				if ranking[0] != baseRanking[0] && delta < report.MinPerturbTop1 {
+					report.MinPerturbTop1 = delta
+				}
+				if !sameTop3(ranking, baseRanking) && delta < report.MinPerturbTop3 {
+					report.MinPerturbTop3 = delta
+				}
+			}
+		}
+	}
+
+	return report
+}
+
+// perturbWeights returns a copy of weights with weights[axis] shifted by
+// delta, and every other weight scaled proportionally so the whole slice
+// still sums to 1.
+func perturbWeights(weights []float64, axis int, delta float64) []float64 {
+	out := make([]float64, len(weights))
+	newAxis := weights[axis] + delta
+	oldRemainder := 1 - weights[axis]
+	newRemainder := 1 - newAxis
+	for i := range weights {
+		if i == axis {
+			out[i] = newAxis
+			continue
+		}
+		if oldRemainder == 0 {
+			out[i] = weights[i]
+			continue
+		}
+		out[i] = weights[i] / oldRemainder * newRemainder
+	}
+	return out
+}
+
+// sensitivityProbeDeltas is the set of perturbation sizes tried when
+// searching for the smallest one that flips the ranking; a single
+// derivative can't answer that on its own since a near-tie only flips
+// once the perturbation crosses some threshold.
+func sensitivityProbeDeltas(perturb float64) []float64 {
+	var deltas []float64
+	for _, mult := range []float64{1, 2, 5, 10, 20, 50, 100} {
+		d := perturb * mult
+		if d >= 1 {
+			break
+		}
+		deltas = append(deltas, d)
+	}
+	return deltas
+}
+
+// rankCities returns city names ordered by descending score.
+func rankCities(names []string, means []float64) []string {
+	type scored struct {
+		name  string
+		score float64
+	}
+	list := make([]scored, len(names))
+	for i, name := range names {
+		list[i] = scored{name, means[i]}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].score > list[j].score
+	})
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = s.name
+	}
+	return out
+}
+
+// sameTop3 reports whether a and b agree on their top 3 cities, in order.
+func sameTop3(a, b []string) bool {
+	n := 3
+	if len(a) < n || len(b) < n {
+		n = len(a)
+		if len(b) < n {
+			n = len(b)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Print logs the sensitivity report: for each axis, the cities most
+// affected by a small change in that axis's weight, then the smallest
+// perturbation (to any single weight) that flips the top-1 or top-3.
+func (r SensitivityReport) Print() {
+	log.Print("Sensitivity")
+
+	axes := make([]string, 0, len(r.Gradient))
+	for axis := range r.Gradient {
+		axes = append(axes, axis)
+	}
+	sort.Strings(axes)
+
+	for _, axis := range axes {
+		log.Printf("  %s", axis)
+		byCity := r.Gradient[axis]
+		names := make([]string, 0, len(byCity))
+		for name := range byCity {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return math.Abs(byCity[names[i]]) > math.Abs(byCity[names[j]])
+		})
+		for _, name := range names {
+			log.Printf("    %-20s d(score)/d(weight) = %+.3f", name, byCity[name])
+		}
+	}
+
+	log.Printf("Smallest weight change that flips top-1:  %.4f", r.MinPerturbTop1)
+	log.Printf("Smallest weight change that changes top-3: %.4f", r.MinPerturbTop3)
+}